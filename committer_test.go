@@ -0,0 +1,129 @@
+package main
+
+import (
+	"testing"
+	"time"
+
+	"github.com/go-git/go-billy/v5/memfs"
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/storage/memory"
+)
+
+// newTestGoGitCommitter sets up an in-memory repo with one initial commit
+// and wraps it in a GoGitCommitter, bypassing NewGoGitCommitter's
+// git.PlainOpen so the test doesn't need a real working directory.
+func newTestGoGitCommitter(t *testing.T) (*GoGitCommitter, *git.Repository) {
+	t.Helper()
+
+	repo, err := git.Init(memory.NewStorage(), memfs.New())
+	if err != nil {
+		t.Fatalf("git.Init: %s", err)
+	}
+
+	wt, err := repo.Worktree()
+	if err != nil {
+		t.Fatalf("Worktree: %s", err)
+	}
+
+	sig := &object.Signature{Name: "test", Email: "test@example.com", When: time.Now()}
+	initHash, err := wt.Commit("initial", &git.CommitOptions{Author: sig, AllowEmptyCommits: true})
+	if err != nil {
+		t.Fatalf("initial commit: %s", err)
+	}
+
+	initCommit, err := repo.CommitObject(initHash)
+	if err != nil {
+		t.Fatalf("CommitObject: %s", err)
+	}
+
+	return &GoGitCommitter{
+		repo: repo,
+		head: initHash,
+		tree: initCommit.TreeHash,
+		sig:  *sig,
+	}, repo
+}
+
+func TestGoGitCommitterChainsParents(t *testing.T) {
+	c, repo := newTestGoGitCommitter(t)
+	initHash := c.head
+
+	day := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	if err := c.Commit(day, 1); err != nil {
+		t.Fatalf("first Commit: %s", err)
+	}
+	firstHash := c.head
+
+	if err := c.Commit(day.AddDate(0, 0, 1), 1); err != nil {
+		t.Fatalf("second Commit: %s", err)
+	}
+	secondHash := c.head
+
+	firstCommit, err := repo.CommitObject(firstHash)
+	if err != nil {
+		t.Fatalf("CommitObject(first): %s", err)
+	}
+	if len(firstCommit.ParentHashes) != 1 || firstCommit.ParentHashes[0] != initHash {
+		t.Errorf("first commit's parent = %v, want [%s]", firstCommit.ParentHashes, initHash)
+	}
+
+	secondCommit, err := repo.CommitObject(secondHash)
+	if err != nil {
+		t.Fatalf("CommitObject(second): %s", err)
+	}
+	if len(secondCommit.ParentHashes) != 1 || secondCommit.ParentHashes[0] != firstHash {
+		t.Errorf("second commit's parent = %v, want [%s]", secondCommit.ParentHashes, firstHash)
+	}
+}
+
+func TestGoGitCommitterTagReadsChainTipBeforeFlush(t *testing.T) {
+	c, repo := newTestGoGitCommitter(t)
+
+	day := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	if err := c.Commit(day, 1); err != nil {
+		t.Fatalf("first Commit: %s", err)
+	}
+	frame0 := c.head
+
+	if err := c.Tag("frame-0"); err != nil {
+		t.Fatalf("Tag: %s", err)
+	}
+
+	// A second commit moves the in-memory chain tip forward, but must not
+	// retroactively move the tag already written for frame-0.
+	if err := c.Commit(day.AddDate(0, 0, 1), 1); err != nil {
+		t.Fatalf("second Commit: %s", err)
+	}
+
+	ref, err := repo.Reference(plumbing.NewTagReferenceName("frame-0"), true)
+	if err != nil {
+		t.Fatalf("Reference(frame-0): %s", err)
+	}
+	if ref.Hash() != frame0 {
+		t.Errorf("frame-0 tag = %s, want %s (the commit forged before Tag was called)", ref.Hash(), frame0)
+	}
+}
+
+func TestGoGitCommitterFlushMovesHead(t *testing.T) {
+	c, repo := newTestGoGitCommitter(t)
+
+	day := time.Date(2020, time.January, 1, 12, 0, 0, 0, time.UTC)
+	if err := c.Commit(day, 1); err != nil {
+		t.Fatalf("Commit: %s", err)
+	}
+	want := c.head
+
+	if err := c.Flush(); err != nil {
+		t.Fatalf("Flush: %s", err)
+	}
+
+	head, err := repo.Head()
+	if err != nil {
+		t.Fatalf("Head: %s", err)
+	}
+	if head.Hash() != want {
+		t.Errorf("HEAD after Flush = %s, want %s", head.Hash(), want)
+	}
+}
@@ -7,68 +7,125 @@ import (
 	"log"
 	"os"
 	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	patternimage "github.com/raydog/github-drawing/pattern/image"
 )
 
 
 const realCommit = "Add script and image file"
 const fakePrefix = "FAKE_COMMIT"
-const dailyCommits = 100
 const DurationDay = time.Hour * 24
 const DurationWeek = DurationDay * 7
 
+// frameSeparator marks the boundary between frames in a single multi-frame
+// pattern file.
+const frameSeparator = "---"
 
-type Pattern struct {
+// numLevels is the number of intensity levels a cell can hold, matching
+// the 5 shades GitHub renders a contribution cell in (0 = no commits).
+const numLevels = 5
+
+// levelGlyphs maps pattern-file characters to intensity levels by position;
+// a glyph positioned past the top level ('#') or an unrecognized character
+// clamps to the top level, so '=' and '#' both mean level 4. Digits '0'-'4' are also accepted directly, so patterns can be
+// authored either way.
+const levelGlyphs = " .:-=#"
+
+func levelForChar(c byte) uint8 {
+	if c >= '0' && c <= '4' {
+		return c - '0'
+	}
+
+	idx := strings.IndexByte(levelGlyphs, c)
+	if idx < 0 || idx > numLevels-1 {
+		idx = numLevels - 1
+	}
+	return uint8(idx)
+}
+
+// Frame is a single 7-row-tall still of a pattern. A Pattern is one or more
+// Frames played back across the contribution graph. Each cell holds an
+// intensity level from 0 (no commits) to numLevels-1 (max).
+type Frame struct {
 	W, H int
-	data []bool
+	data []uint8
 }
 
 // Returns index of point, or -1 if out of bounds:
-func (p *Pattern) getIdx(x, y int) int {
-	if y < 0 || y >= p.H {
+func (f *Frame) getIdx(x, y int) int {
+	if y < 0 || y >= f.H {
 		return -1
 	}
-	for ; x < 0 ; x += p.W {}
+	for ; x < 0 ; x += f.W {}
+
+	return y * f.W + x % f.W
+}
 
-	return y * p.W + x % p.W
+type Pattern struct {
+	Frames []Frame
 }
 
-func (p *Pattern) Draw(start time.Time, numWeeks int) error {
+func (p *Pattern) Draw(start time.Time, weeksPerFrame int, tagFrames bool, committer Committer, levelCounts [numLevels]int, cfg *Config) error {
 	drawOrigin := time.Date(2015, time.April, 26, 12, 0, 0, 0, time.UTC)
-	offset := int(start.Sub(drawOrigin).Hours() / 24 / 7 + 0.5)
-	
-	d := start.Add(-DurationDay)
-	for x:=offset; x<offset+numWeeks; x++ {
-		for y:=0; y<7; y++ {
-			d = d.Add(DurationDay)
-			dataIdx := p.getIdx(x, y)
-			if dataIdx < 0 || !p.data[dataIdx] {
-				log.Println("Skipping commits for", d)
-				continue
-			}
-			log.Println("Building commits for", d)
-			for n:=1; n<=dailyCommits; n++ {
-				err := forgeCommit(d, n)
-				if err != nil {
-					return err
+
+	frameStart := start
+	for frameIdx, frame := range p.Frames {
+		offset := int(frameStart.Sub(drawOrigin).Hours() / 24 / 7 + 0.5)
+
+		d := frameStart.Add(-DurationDay)
+		for x:=offset; x<offset+weeksPerFrame; x++ {
+			cfg.Logger.Info("week_started", "frame", frameIdx, "week", x)
+			cfg.Progress.WeekStarted(x)
+
+			for y:=0; y<7; y++ {
+				d = d.Add(DurationDay)
+				dataIdx := frame.getIdx(x, y)
+				var level uint8
+				if dataIdx >= 0 {
+					level = frame.data[dataIdx]
 				}
+				if level == 0 {
+					cfg.Logger.Debug("day_skipped", "date", d)
+					continue
+				}
+				count := levelCounts[level]
+				for n:=1; n<=count; n++ {
+					err := committer.Commit(d, n)
+					if err != nil {
+						return err
+					}
+					cfg.Logger.Debug("commit_forged", "date", d, "n", n)
+					cfg.Progress.CommitForged()
+				}
+			}
+		}
+
+		if tagFrames {
+			tag := fmt.Sprintf("frame-%d", frameIdx)
+			cfg.Logger.Info("frame_tagged", "tag", tag)
+			if err := committer.Tag(tag); err != nil {
+				return err
 			}
 		}
+
+		frameStart = frameStart.Add(DurationWeek * time.Duration(weeksPerFrame))
 	}
 	return nil
 }
 
 
-func newPatternFromFile(fpath string) (*Pattern, error) {
-	contents, err := ioutil.ReadFile(fpath)
-	if err != nil {
-		return nil, err
+func newFrameFromText(text string) (*Frame, error) {
+	parsed := strings.Split(text, "\n")
+	for len(parsed) > 0 && parsed[len(parsed)-1] == "" {
+		parsed = parsed[:len(parsed)-1]
 	}
-
-	parsed := strings.Split(string(contents), "\n")
 	if len(parsed) > 7 {
-		return nil, fmt.Errorf("File %s has too many lines: %d", fpath, len(parsed))
+		return nil, fmt.Errorf("frame has too many lines: %d", len(parsed))
 	}
 
 	maxWidth := -1
@@ -82,21 +139,104 @@ func newPatternFromFile(fpath string) (*Pattern, error) {
 		parsed[idx] = Pad(line, maxWidth)
 	}
 
-	out := new(Pattern)
+	out := new(Frame)
 	out.H = len(parsed)
 	out.W = maxWidth
-	out.data = make([]bool, out.W * out.H)
+	out.data = make([]uint8, out.W * out.H)
 
 	for x:=0; x<out.W; x++ {
 		for y:=0; y<out.H; y++ {
-			hasSomething := parsed[y][x] != ' '
-			out.data[out.getIdx(x, y)] = hasSomething
+			out.data[out.getIdx(x, y)] = levelForChar(parsed[y][x])
+		}
+	}
+
+	return out, nil
+}
+
+// newPatternFromFile loads a Pattern from fpath. fpath may be:
+//   - a single text file holding one frame
+//   - a single text file holding multiple frames, separated by a line
+//     containing only "---"
+//   - a directory of "*.txt" files, one frame per file, played back in
+//     filename order
+//   - a .png or .jpg/.jpeg image, dithered into a single frame weeksWide
+//     cells wide (see newPatternFromImage)
+func newPatternFromFile(fpath string, weeksWide int) (*Pattern, error) {
+	switch strings.ToLower(filepath.Ext(fpath)) {
+	case ".png", ".jpg", ".jpeg":
+		return newPatternFromImage(fpath, weeksWide)
+	}
+
+	info, err := os.Stat(fpath)
+	if err != nil {
+		return nil, err
+	}
+
+	var frameTexts []string
+	if info.IsDir() {
+		entries, err := ioutil.ReadDir(fpath)
+		if err != nil {
+			return nil, err
+		}
+
+		var names []string
+		for _, entry := range entries {
+			if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".txt") {
+				continue
+			}
+			names = append(names, entry.Name())
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			contents, err := ioutil.ReadFile(filepath.Join(fpath, name))
+			if err != nil {
+				return nil, err
+			}
+			frameTexts = append(frameTexts, string(contents))
 		}
+	} else {
+		contents, err := ioutil.ReadFile(fpath)
+		if err != nil {
+			return nil, err
+		}
+		frameTexts = strings.Split(string(contents), "\n"+frameSeparator+"\n")
+	}
+
+	if len(frameTexts) == 0 {
+		return nil, fmt.Errorf("file %s has no frames", fpath)
+	}
+
+	out := new(Pattern)
+	for _, text := range frameTexts {
+		frame, err := newFrameFromText(text)
+		if err != nil {
+			return nil, fmt.Errorf("file %s: %s", fpath, err)
+		}
+		out.Frames = append(out.Frames, *frame)
 	}
 
 	return out, nil
 }
 
+// newPatternFromImage decodes the PNG or JPEG at fpath and dithers it into
+// a single Frame weeksWide cells wide by 7 cells tall (one column per
+// week, one row per weekday), so it can be drawn like any other Pattern.
+func newPatternFromImage(fpath string, weeksWide int) (*Pattern, error) {
+	f, err := os.Open(fpath)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	data, err := patternimage.Dither(f, weeksWide, 7)
+	if err != nil {
+		return nil, fmt.Errorf("file %s: %s", fpath, err)
+	}
+
+	return &Pattern{Frames: []Frame{{W: weeksWide, H: 7, data: data}}}, nil
+}
+
 func Pad(str string, size int) string {
 	for ; len(str) < size ; {
 		str += " "
@@ -106,7 +246,7 @@ func Pad(str string, size int) string {
 
 func forgeCommit(date time.Time, num int) error {
 	year, month, day := date.Date()
-	
+
 	msg := fmt.Sprintf("%s: %4d-%02d-%02d #%d", fakePrefix, year, month, day, num)
 	cmd := exec.Command("git", "commit", "--allow-empty", "-m", msg)
 
@@ -123,8 +263,8 @@ func forgeCommit(date time.Time, num int) error {
 	return nil
 }
 
-// Will squash the entire repo into a single commit: 
-func squashHistory() error {
+// Will squash the entire repo into a single commit:
+func squashHistory(cfg *Config) error {
 	var err error
 
 	// Drop all commits, but leave directory contents:
@@ -145,6 +285,70 @@ func squashHistory() error {
 		return err
 	}
 
+	cfg.Logger.Info("squash_complete")
+	cfg.Progress.SquashComplete()
+	return nil
+}
+
+// parseLevels parses a "-levels" flag value, a comma-separated list of
+// numLevels-1 commit counts, one per intensity level from 1 up (level 0 is
+// always 0 commits and isn't included).
+func parseLevels(s string) ([numLevels]int, error) {
+	var out [numLevels]int
+	parts := strings.Split(s, ",")
+	if len(parts) != numLevels-1 {
+		return out, fmt.Errorf("-levels needs %d comma-separated counts, got %d", numLevels-1, len(parts))
+	}
+
+	for i, part := range parts {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil {
+			return out, fmt.Errorf("-levels: %s", err)
+		}
+		out[i+1] = n
+	}
+	return out, nil
+}
+
+// runCalibrate inspects the current repo's commit history and suggests a
+// -levels mapping whose top level matches the busiest day seen so far, so
+// a pattern renders at the intended contrast against real activity.
+func runCalibrate() error {
+	out, err := exec.Command("git", "log", "--all", "--format=%ad", "--date=short").Output()
+	if err != nil {
+		return err
+	}
+
+	counts := map[string]int{}
+	for _, day := range strings.Split(strings.TrimSpace(string(out)), "\n") {
+		if day == "" {
+			continue
+		}
+		counts[day]++
+	}
+
+	max := 0
+	for _, c := range counts {
+		if c > max {
+			max = c
+		}
+	}
+	if max == 0 {
+		return fmt.Errorf("no commits found to calibrate against")
+	}
+
+	ratios := [numLevels - 1]float64{0.05, 0.15, 0.4, 1.0}
+	levels := make([]string, len(ratios))
+	for i, r := range ratios {
+		count := int(float64(max)*r + 0.5)
+		if count < 1 {
+			count = 1
+		}
+		levels[i] = strconv.Itoa(count)
+	}
+
+	fmt.Printf("Busiest day in history: %d commits\n", max)
+	fmt.Printf("Suggested: -levels=%s\n", strings.Join(levels, ","))
 	return nil
 }
 
@@ -156,16 +360,49 @@ func getOrigin(weeksAgo int) time.Time {
 }
 
 func main() {
+	if len(os.Args) > 1 && os.Args[1] == "calibrate" {
+		if err := runCalibrate(); err != nil {
+			log.Fatal("Couldn't calibrate:", err)
+		}
+		return
+	}
+
 	var err error
 	var fileName string
 	var numWeeks int
 	var resetRepo bool
 	var showHelp bool
+	var tagFrames bool
+	var weeksPerFrame int
+	var backend string
+	var levelsFlag string
+	var remote string
+	var branch string
+	var forceWithLease bool
+	var yes bool
+	var dryRun bool
+	var logFormat string
+	var logLevel string
+	var quiet bool
 
 	flag.StringVar(&fileName, "pattern", "", "The pattern file to use. (REQUIRED)")
-	flag.IntVar(&numWeeks, "weeks", 1, "The number of weeks to generate. Default: 1")
+	flag.IntVar(&numWeeks, "weeks", 1, "The number of weeks to generate. Default: 1. Ignored for multi-frame patterns; use -fps-weeks instead.")
 	flag.BoolVar(&resetRepo, "reset", false, "Whether to reset the git repo first. Default: false")
 	flag.BoolVar(&showHelp, "help", false, "Shows this help message")
+	flag.BoolVar(&tagFrames, "frames", false, "Tag a checkpoint (refs/tags/frame-N) after each frame, for sequential checkout. Default: false")
+	flag.IntVar(&weeksPerFrame, "fps-weeks", 1, "How many weeks of history each frame of a multi-frame pattern occupies. Default: 1")
+	flag.StringVar(&backend, "backend", "gogit", "Commit-forging backend: \"gogit\" (default, writes commit objects in-process) or \"shell\" (forks `git commit` per commit; slower, easier to debug)")
+	// No -jobs flag for the gogit backend: see the GoGitCommitter doc comment
+	// in committer.go for why a commit chain can't be built in parallel.
+	flag.StringVar(&levelsFlag, "levels", "5,15,40,100", "Commit counts for intensity levels 1-4, comma-separated (level 0 is always empty). Default: 5,15,40,100")
+	flag.StringVar(&remote, "remote", "", "Git remote to push the forged history to after drawing. Default: \"\" (don't push)")
+	flag.StringVar(&branch, "branch", "main", "Remote branch to push to, when -remote is set. Default: main")
+	flag.BoolVar(&forceWithLease, "force-with-lease", false, "Push with --force-with-lease. Forged history usually needs this, since it rewrites whatever's already on the remote. Default: false")
+	flag.BoolVar(&yes, "yes", false, "Skip the push confirmation prompt. Default: false")
+	flag.BoolVar(&dryRun, "dry-run", false, "Print the contribution-graph preview and exit without forging any commits. Default: false")
+	flag.StringVar(&logFormat, "log-format", "text", "Log format: \"text\" or \"json\". JSON emits week_started, commit_forged (at -log-level=debug) and squash_complete events for CI consumers. Default: text")
+	flag.StringVar(&logLevel, "log-level", "info", "Log level: debug, info, warn, or error. Default: info")
+	flag.BoolVar(&quiet, "quiet", false, "Suppress the progress bar. Default: false")
 	flag.Parse()
 
 	if showHelp {
@@ -177,24 +414,84 @@ func main() {
 		log.Fatal("Pattern file is required")
 	}
 
+	levelCounts, err := parseLevels(levelsFlag)
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	log.Println("Loading pattern file...")
-	pattern, err := newPatternFromFile(fileName)
+	pattern, err := newPatternFromFile(fileName, numWeeks)
 	if err != nil {
 		log.Fatal("Couldn't load pattern:", err)
 	}
 
+	if len(pattern.Frames) <= 1 {
+		// Single-frame patterns keep the original -weeks behavior.
+		weeksPerFrame = numWeeks
+	}
+
+	if dryRun {
+		start, end := dateRange(getOrigin(weeksPerFrame*len(pattern.Frames)), weeksPerFrame, len(pattern.Frames))
+		fmt.Print(RenderPreview(pattern, weeksPerFrame))
+		fmt.Printf("Date range: %s to %s\n", start.Format("2006-01-02"), end.Format("2006-01-02"))
+		fmt.Printf("Total commits: %d\n", totalCommits(pattern, weeksPerFrame, levelCounts))
+		return
+	}
+
+	cfg, err := NewConfig(logFormat, logLevel, quiet, totalCommits(pattern, weeksPerFrame, levelCounts))
+	if err != nil {
+		log.Fatal(err)
+	}
+
 	if resetRepo {
-		log.Println("Squashing prior history into a single commit...")
-		err = squashHistory()
+		cfg.Logger.Info("squashing_history")
+		err = squashHistory(cfg)
 		if err != nil {
 			log.Fatal("Couldn't flatten history:", err)
 		}
 	}
 
-	d := getOrigin(numWeeks)
-	
-	err = pattern.Draw(d, numWeeks)
+	d := getOrigin(weeksPerFrame * len(pattern.Frames))
+
+	var committer Committer
+	var gitCommitter *GoGitCommitter
+	switch backend {
+	case "shell":
+		committer = ShellCommitter{}
+	case "gogit":
+		gitCommitter, err = NewGoGitCommitter(".")
+		if err != nil {
+			log.Fatal("Couldn't open repo for gogit backend:", err)
+		}
+		committer = gitCommitter
+	default:
+		log.Fatalf("Unknown -backend %q (want \"gogit\" or \"shell\")", backend)
+	}
+
+	err = pattern.Draw(d, weeksPerFrame, tagFrames, committer, levelCounts, cfg)
 	if err != nil {
 		log.Fatal("Couldn't draw pattern:", err)
 	}
-}
\ No newline at end of file
+
+	if gitCommitter != nil {
+		if err := gitCommitter.Flush(); err != nil {
+			log.Fatal("Couldn't flush forged commits to HEAD:", err)
+		}
+	}
+
+	if remote != "" {
+		start, end := dateRange(d, weeksPerFrame, len(pattern.Frames))
+		fmt.Print(RenderPreview(pattern, weeksPerFrame))
+		fmt.Printf("Date range: %s to %s\n", start.Format("2006-01-02"), end.Format("2006-01-02"))
+		fmt.Printf("Total commits: %d\n", totalCommits(pattern, weeksPerFrame, levelCounts))
+
+		if !yes && !confirm(fmt.Sprintf("Push to %s %s?", remote, branch)) {
+			log.Fatal("Aborted before publishing")
+		}
+
+		cfg.Logger.Info("pushing", "remote", remote, "branch", branch)
+		if err := publish(remote, branch, forceWithLease); err != nil {
+			log.Fatal("Couldn't publish:", err)
+		}
+	}
+}
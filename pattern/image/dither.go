@@ -0,0 +1,123 @@
+// Package image converts a raster image into the intensity levels the
+// generator's Pattern understands, via Floyd-Steinberg dithering.
+package image
+
+import (
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+)
+
+// NumLevels is the number of intensity levels a dithered cell can hold,
+// matching the levels the main package's Frame type stores (0 = empty).
+const NumLevels = 5
+
+// Dither decodes a PNG or JPEG from r, resizes it to a width x height grid
+// of cells, and dithers it down to a width*height slice of intensity
+// levels (0 to NumLevels-1) in raster order using Floyd-Steinberg error
+// diffusion:
+//
+//	for each pixel in raster order:
+//		quantize to the nearest level q
+//		e := old - levelValue(q)
+//		distribute 7/16 * e to the right neighbor
+//		distribute 3/16 * e to the bottom-left neighbor
+//		distribute 5/16 * e to the bottom neighbor
+//		distribute 1/16 * e to the bottom-right neighbor
+//		(skipping any neighbor that falls outside the grid)
+//
+// Images whose average luminance is closer to white than black are
+// assumed to be dark-on-light (e.g. a line drawing) and are inverted, so
+// that the drawn subject - not the background - ends up at the high
+// intensity levels.
+func Dither(r io.Reader, width, height int) ([]uint8, error) {
+	src, _, err := image.Decode(r)
+	if err != nil {
+		return nil, fmt.Errorf("decoding image: %s", err)
+	}
+
+	lum := resizeToLuminance(src, width, height)
+
+	var total float64
+	for _, l := range lum {
+		total += l
+	}
+	darkOnLight := total/float64(len(lum)) > 0.5
+
+	const step = 1.0 / float64(NumLevels-1)
+	out := make([]uint8, width*height)
+
+	for y := 0; y < height; y++ {
+		for x := 0; x < width; x++ {
+			idx := y*width + x
+
+			level := quantize(lum[idx], step)
+			out[idx] = uint8(level)
+
+			diffuseError(lum, width, height, x, y, lum[idx]-float64(level)*step)
+		}
+	}
+
+	if darkOnLight {
+		for i, level := range out {
+			out[i] = uint8(NumLevels-1) - level
+		}
+	}
+
+	return out, nil
+}
+
+func quantize(v, step float64) int {
+	level := int(v/step + 0.5)
+	if level < 0 {
+		level = 0
+	}
+	if level > NumLevels-1 {
+		level = NumLevels - 1
+	}
+	return level
+}
+
+// diffuseError spreads Floyd-Steinberg quantization error from (x, y) to
+// its four not-yet-visited neighbors, skipping any that fall outside the
+// width x height grid.
+func diffuseError(lum []float64, width, height, x, y int, e float64) {
+	add := func(nx, ny int, weight float64) {
+		if nx < 0 || nx >= width || ny < 0 || ny >= height {
+			return
+		}
+		lum[ny*width+nx] += e * weight
+	}
+
+	add(x+1, y, 7.0/16)
+	add(x-1, y+1, 3.0/16)
+	add(x, y+1, 5.0/16)
+	add(x+1, y+1, 1.0/16)
+}
+
+// resizeToLuminance nearest-neighbor samples src down to a width x height
+// grid and converts each sampled pixel to luminance in [0, 1].
+func resizeToLuminance(src image.Image, width, height int) []float64 {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+
+	out := make([]float64, width*height)
+	for y := 0; y < height; y++ {
+		sy := bounds.Min.Y + y*srcH/height
+		for x := 0; x < width; x++ {
+			sx := bounds.Min.X + x*srcW/width
+			out[y*width+x] = luminance(src.At(sx, sy))
+		}
+	}
+	return out
+}
+
+func luminance(c interface {
+	RGBA() (r, g, b, a uint32)
+}) float64 {
+	r, g, b, _ := c.RGBA()
+	// Rec. 601 luma, operating on the 16-bit channel values RGBA() returns.
+	return (0.299*float64(r) + 0.587*float64(g) + 0.114*float64(b)) / 0xffff
+}
@@ -0,0 +1,97 @@
+package image
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+func encodeGray(t *testing.T, w, h int, px func(x, y int) uint8) *bytes.Buffer {
+	t.Helper()
+	img := image.NewGray(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.SetGray(x, y, color.Gray{Y: px(x, y)})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatalf("encoding test png: %s", err)
+	}
+	return &buf
+}
+
+func TestQuantizeRounding(t *testing.T) {
+	step := 1.0 / float64(NumLevels-1)
+	cases := []struct {
+		v    float64
+		want int
+	}{
+		{0, 0},
+		{1, NumLevels - 1},
+		{step/2 - 0.001, 0}, // rounds down just below the halfway point
+		{step / 2, 1},       // rounds up at the halfway point
+		{-1, 0},             // clamps below range
+		{2, NumLevels - 1},  // clamps above range
+	}
+	for _, c := range cases {
+		if got := quantize(c.v, step); got != c.want {
+			t.Errorf("quantize(%v, %v) = %d, want %d", c.v, step, got, c.want)
+		}
+	}
+}
+
+func TestDitherInvertsDarkOnLight(t *testing.T) {
+	// Mostly white with a single black pixel: average luminance is well
+	// above the 0.5 threshold, so this is treated as dark-on-light and
+	// inverted. The black pixel (the actual subject) should end up at the
+	// top intensity level, not the bottom.
+	buf := encodeGray(t, 2, 2, func(x, y int) uint8 {
+		if x == 0 && y == 0 {
+			return 0
+		}
+		return 255
+	})
+
+	out, err := Dither(buf, 2, 2)
+	if err != nil {
+		t.Fatalf("Dither: %s", err)
+	}
+
+	if out[0] != NumLevels-1 {
+		t.Errorf("black pixel on light background: got level %d, want %d", out[0], NumLevels-1)
+	}
+	for i := 1; i < len(out); i++ {
+		if out[i] != 0 {
+			t.Errorf("white background pixel %d: got level %d, want 0", i, out[i])
+		}
+	}
+}
+
+func TestDitherLeavesLightOnDarkAlone(t *testing.T) {
+	// Mostly black with a single white pixel: average luminance is well
+	// below the threshold, so no inversion happens and the white pixel
+	// (the subject) stays at the top level directly.
+	buf := encodeGray(t, 2, 2, func(x, y int) uint8 {
+		if x == 0 && y == 0 {
+			return 255
+		}
+		return 0
+	})
+
+	out, err := Dither(buf, 2, 2)
+	if err != nil {
+		t.Fatalf("Dither: %s", err)
+	}
+
+	if out[0] != NumLevels-1 {
+		t.Errorf("white pixel on dark background: got level %d, want %d", out[0], NumLevels-1)
+	}
+	for i := 1; i < len(out); i++ {
+		if out[i] != 0 {
+			t.Errorf("black background pixel %d: got level %d, want 0", i, out[i])
+		}
+	}
+}
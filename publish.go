@@ -0,0 +1,89 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+	"time"
+)
+
+// previewGlyphs is the fixed numLevels-long alphabet the contribution-graph
+// preview renders in, independent of whichever characters a pattern file
+// happened to be authored with.
+var previewGlyphs = [numLevels]byte{' ', '.', ':', '-', '#'}
+
+// RenderPreview draws an ASCII rendition of the contribution graph a
+// pattern will produce: 7 rows (weekdays) by weeksPerFrame columns per
+// frame, using the same 5-level glyphs pattern files are authored in.
+func RenderPreview(p *Pattern, weeksPerFrame int) string {
+	var b strings.Builder
+	for frameIdx, frame := range p.Frames {
+		if len(p.Frames) > 1 {
+			fmt.Fprintf(&b, "frame %d:\n", frameIdx)
+		}
+		for y := 0; y < 7; y++ {
+			for x := 0; x < weeksPerFrame; x++ {
+				var level uint8
+				if idx := frame.getIdx(x, y); idx >= 0 {
+					level = frame.data[idx]
+				}
+				b.WriteByte(previewGlyphs[level])
+			}
+			b.WriteByte('\n')
+		}
+	}
+	return b.String()
+}
+
+// totalCommits sums the commits Draw would forge for a pattern under the
+// given level->count mapping.
+func totalCommits(p *Pattern, weeksPerFrame int, levelCounts [numLevels]int) int {
+	total := 0
+	for _, frame := range p.Frames {
+		for x := 0; x < weeksPerFrame; x++ {
+			for y := 0; y < 7; y++ {
+				idx := frame.getIdx(x, y)
+				if idx < 0 {
+					continue
+				}
+				total += levelCounts[frame.data[idx]]
+			}
+		}
+	}
+	return total
+}
+
+// dateRange returns the first and last day a pattern will occupy when
+// drawn starting at start.
+func dateRange(start time.Time, weeksPerFrame, numFrames int) (time.Time, time.Time) {
+	end := start.Add(DurationWeek*time.Duration(weeksPerFrame*numFrames) - DurationDay)
+	return start, end
+}
+
+// confirm prints prompt and blocks for a y/N answer on stdin, returning
+// true only for an explicit "y" or "yes".
+func confirm(prompt string) bool {
+	fmt.Print(prompt, " [y/N] ")
+	line, _ := bufio.NewReader(os.Stdin).ReadString('\n')
+	line = strings.ToLower(strings.TrimSpace(line))
+	return line == "y" || line == "yes"
+}
+
+// publish pushes HEAD to branch on remote. forceWithLease is almost always
+// needed, since forged history rewrites whatever the remote already has.
+func publish(remote, branch string, forceWithLease bool) error {
+	args := []string{"push"}
+	if forceWithLease {
+		args = append(args, "--force-with-lease")
+	}
+	args = append(args, remote, fmt.Sprintf("HEAD:refs/heads/%s", branch))
+
+	out, err := exec.Command("git", args...).CombinedOutput()
+	if err != nil {
+		fmt.Println(string(out))
+		return err
+	}
+	return nil
+}